@@ -0,0 +1,56 @@
+package darwin
+
+import "fmt"
+
+// ClickHouseDialect a Dialect configured for ClickHouse
+type ClickHouseDialect struct {
+	Options DialectOptions
+}
+
+// CreateTableSQL returns the SQL to create the schema table
+func (c ClickHouseDialect) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    version        Float64,
+                    description    String,
+                    checksum       String,
+                    applied_at     DateTime64(3),
+                    execution_time Float64
+                ) ENGINE = MergeTree() ORDER BY version;`, c.Options.tableName())
+}
+
+// InsertSQL returns the SQL to insert a new migration in the schema table
+func (c ClickHouseDialect) InsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time
+                )
+            VALUES (?, ?, ?, ?, ?);`, c.Options.tableName())
+}
+
+// AllSQL returns a SQL to get all entries in the table
+func (c ClickHouseDialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time
+            FROM
+                %s
+            ORDER BY version ASC;`, c.Options.tableName())
+}
+
+// DeleteSQL returns the SQL to remove a migration from the schema table
+func (c ClickHouseDialect) DeleteSQL() string {
+	return fmt.Sprintf(`ALTER TABLE %s DELETE WHERE version = ?;`, c.Options.tableName())
+}
+
+// nativeAppliedAt marks ClickHouseDialect as a NativeTimeDialect: its
+// applied_at column is DateTime64(3), a genuine timestamp, not the
+// integer Unix timestamp other dialects use.
+func (c ClickHouseDialect) nativeAppliedAt() {}
@@ -2,9 +2,13 @@ package darwin
 
 import (
 	"bufio"
+	"context"
 	"crypto/md5"
+	"database/sql"
 	"embed"
+	"errors"
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -16,10 +20,8 @@ import (
 type Status int
 
 const (
-	// Ignored means that the migrations was not applied to the database
-	Ignored Status = iota
 	// Applied means that the migrations was successfully applied to the database
-	Applied
+	Applied Status = iota
 	// Pending means that the migrations is a new migration and it is waiting to be applied to the database
 	Pending
 	// Error means that the migration could not be applied to the database
@@ -28,8 +30,6 @@ const (
 
 func (s Status) String() string {
 	switch s {
-	case Ignored:
-		return "IGNORED"
 	case Applied:
 		return "APPLIED"
 	case Pending:
@@ -49,15 +49,37 @@ type Migration struct {
 	Version     float64
 	Description string
 	Script      string
+	// DownScript is the script used to revert this migration. It is
+	// populated when the source contains a "-- Direction: Down" (or
+	// "-- +migrate Down") section and is required by Rollback/RollbackLast.
+	DownScript string
+
+	// Up and Down, when set, make this a Go migration: Script and
+	// DownScript are ignored and Up/Down run inside a transaction
+	// instead. Build one with RegisterGoMigration.
+	Up   func(ctx context.Context, tx *sql.Tx) error
+	Down func(ctx context.Context, tx *sql.Tx) error
+
+	// ChecksumOverride, when set, is hashed by Checksum instead of
+	// Script. Go migrations have no script body, so RegisterGoMigration
+	// sets this to a caller-supplied stable identifier instead.
+	ChecksumOverride string
 }
 
-// Checksum calculate the Script md5
+// Checksum calculate the Script md5, or the md5 of ChecksumOverride for
+// Go migrations registered through RegisterGoMigration.
 func (m Migration) Checksum() string {
-	return fmt.Sprintf("%x", md5.Sum([]byte(m.Script)))
+	script := m.Script
+	if m.ChecksumOverride != "" {
+		script = m.ChecksumOverride
+	}
+
+	return fmt.Sprintf("%x", md5.Sum([]byte(script)))
 }
 
-// MigrationInfo is a struct used in the infoChan to inform clients about
-// the migration being applied.
+// MigrationInfo is a struct used in the progress channel returned by
+// MigrateWithProgress to inform clients about the migration being
+// applied.
 type MigrationInfo struct {
 	Status    Status
 	Error     error
@@ -75,16 +97,57 @@ func (d Darwin) Validate() error {
 	return Validate(d.driver, d.migrations)
 }
 
+// ValidateContext is the context-aware variant of Validate.
+func (d Darwin) ValidateContext(ctx context.Context) error {
+	return ValidateContext(ctx, d.driver, d.migrations)
+}
+
 // Migrate executes the missing migrations in database
 func (d Darwin) Migrate() error {
 	return Migrate(d.driver, d.migrations)
 }
 
+// MigrateWithOptions executes the missing migrations in database, like
+// Migrate, while honoring the behavior configured in opts.
+func (d Darwin) MigrateWithOptions(opts MigrateOptions) error {
+	return MigrateWithOptions(d.driver, d.migrations, opts)
+}
+
+// MigrateContext is the context-aware variant of Migrate: ctx bounds the
+// whole call and opts configures behavior such as StatementTimeout.
+func (d Darwin) MigrateContext(ctx context.Context, opts MigrateOptions) error {
+	return MigrateContext(ctx, d.driver, d.migrations, opts)
+}
+
+// MigrateWithProgress behaves like MigrateContext, but streams a
+// MigrationInfo for each planned migration to the returned channel as it
+// is applied, instead of blocking until every migration is done.
+func (d Darwin) MigrateWithProgress(ctx context.Context) (<-chan MigrationInfo, <-chan error) {
+	return MigrateWithProgress(ctx, d.driver, d.migrations, MigrateOptions{})
+}
+
+// Rollback reverts every applied migration with a version greater than
+// targetVersion, running their down scripts from the highest version down
+func (d Darwin) Rollback(targetVersion float64) error {
+	return Rollback(d.driver, d.migrations, targetVersion)
+}
+
+// RollbackLast reverts the n applied migrations with the highest versions,
+// running their down scripts from the highest version down
+func (d Darwin) RollbackLast(n int) error {
+	return RollbackLast(d.driver, d.migrations, n)
+}
+
 // Info returns the status of all migrations
 func (d Darwin) Info() ([]MigrationInfo, error) {
 	return Info(d.driver, d.migrations)
 }
 
+// InfoContext is the context-aware variant of Info.
+func (d Darwin) InfoContext(ctx context.Context) ([]MigrationInfo, error) {
+	return InfoContext(ctx, d.driver, d.migrations)
+}
+
 // New returns a new Darwin struct
 func New(driver Driver, migrations []Migration) Darwin {
 	return Darwin{
@@ -109,15 +172,40 @@ func ParseMigrationsDirFiles(fsys embed.FS, dirName string) ([]Migration, error)
 			return nil, err
 		}
 
-		migs := ParseMigrations(string(readFile))
+		migs := parseMigrationFile(file.Name(), string(readFile))
 		migrations = append(migrations, migs...)
 	}
 
 	return migrations, nil
 }
 
+// timestampFilenameRE matches goose/sql-migrate style migration
+// filenames such as "20240115093000_add_users.sql": a numeric timestamp
+// version, an underscore-separated description, and the .sql extension.
+var timestampFilenameRE = regexp.MustCompile(`^(\d{8,})_(.+)\.sql$`)
+
+// parseMigrationFile parses a single migration file's contents. Files
+// named like "20240115093000_add_users.sql" are treated as one migration
+// whose version is the leading timestamp, avoiding the awkward 1.1/1.11
+// ordering float versions force; anything else falls back to the
+// "-- Version:" delimited format parsed by ParseMigrations.
+func parseMigrationFile(name, contents string) []Migration {
+	match := timestampFilenameRE.FindStringSubmatch(name)
+	if match == nil {
+		return ParseMigrations(contents)
+	}
+
+	description := strings.ReplaceAll(match[2], "_", " ")
+	header := fmt.Sprintf("--Version: %s\n--Description: %s\n", match[1], description)
+
+	return ParseMigrations(header + contents)
+}
+
 // ParseMigrations takes a string that represents a text formatted set
-// of migrations and parse them for use.
+// of migrations and parse them for use. A migration may carry both an up
+// and a down script: lines are collected into the down script after a
+// "-- Direction: Down" (or goose/sql-migrate style "-- +migrate Down")
+// marker, and back into the up script on the matching "Up" marker.
 func ParseMigrations(s string) []Migration {
 	var migrations []Migration
 
@@ -125,17 +213,28 @@ func ParseMigrations(s string) []Migration {
 	scanner.Split(bufio.ScanLines)
 
 	var m Migration
-	var script string
+	var upScript, downScript string
+	direction := "up"
+
 	for scanner.Scan() {
 		v := scanner.Text()
+
+		if dir, ok := directionFromMarker(v); ok {
+			direction = dir
+			continue
+		}
+
 		switch {
 		case strings.HasPrefix(strings.Replace(strings.ToLower(v), " ", "", 1), "--version:"):
 
-			m.Script = script
+			m.Script = upScript
+			m.DownScript = downScript
 			migrations = append(migrations, m)
 
 			m = Migration{}
-			script = ""
+			upScript = ""
+			downScript = ""
+			direction = "up"
 
 			f, err := strconv.ParseFloat(strings.TrimSpace(v[11:]), 64)
 			if err != nil {
@@ -147,17 +246,49 @@ func ParseMigrations(s string) []Migration {
 		case strings.HasPrefix(strings.Replace(strings.ToLower(v), " ", "", 1), "--description:"):
 			m.Description = strings.TrimSpace(v[15:])
 
+		case direction == "down":
+			downScript += v + "\n"
+
 		default:
-			script += v + "\n"
+			upScript += v + "\n"
 		}
 	}
 
-	m.Script = script
+	m.Script = upScript
+	m.DownScript = downScript
 	migrations = append(migrations, m)
 
 	return migrations[1:]
 }
 
+// directionFromMarker reports whether the line is a direction marker
+// ("-- Direction: Down" or "-- +migrate Down", up to case and spacing) and,
+// if so, which direction it switches the parser to.
+func directionFromMarker(line string) (string, bool) {
+	normalized := strings.TrimSpace(strings.ToLower(line))
+
+	switch normalized {
+	case "-- +migrate up":
+		return "up", true
+	case "-- +migrate down":
+		return "down", true
+	}
+
+	prefix := strings.Replace(normalized, " ", "", 1)
+	if !strings.HasPrefix(prefix, "--direction:") {
+		return "", false
+	}
+
+	switch strings.TrimSpace(strings.TrimPrefix(prefix, "--direction:")) {
+	case "up":
+		return "up", true
+	case "down":
+		return "down", true
+	default:
+		return "", false
+	}
+}
+
 // DuplicateMigrationVersionError is used to report when the migration list has duplicated entries
 type DuplicateMigrationVersionError struct {
 	Version float64
@@ -194,8 +325,33 @@ func (i InvalidChecksumError) Error() string {
 	return fmt.Sprintf("Invalid cheksum for migration %f", i.Version)
 }
 
+// NoDownScriptError is used to report when Rollback/RollbackLast would
+// have to revert a migration that has neither a DownScript nor a Down
+// function, such as one written in the original "-- Version:" format with
+// no "-- Direction: Down" section.
+type NoDownScriptError struct {
+	Version float64
+}
+
+func (n NoDownScriptError) Error() string {
+	return fmt.Sprintf("Migration %f has no down script to roll back.", n.Version)
+}
+
 // Validate if the database migrations are applied and consistent
 func Validate(d Driver, migrations []Migration) error {
+	return ValidateContext(context.Background(), d, migrations)
+}
+
+// ValidateContext is the context-aware variant of Validate.
+func ValidateContext(ctx context.Context, d Driver, migrations []Migration) error {
+	return validate(ctx, d, migrations, false, nil)
+}
+
+// validate is the shared implementation behind Validate/ValidateContext
+// and the validation step of migrate. When ignoreUnknown is true, applied
+// versions missing from migrations are logged through logger, if set,
+// instead of failing with RemovedMigrationError.
+func validate(ctx context.Context, d Driver, migrations []Migration, ignoreUnknown bool, logger Logger) error {
 	sort.Sort(byMigrationVersion(migrations))
 
 	if version, invalid := isInvalidVersion(migrations); invalid {
@@ -206,14 +362,22 @@ func Validate(d Driver, migrations []Migration) error {
 		return DuplicateMigrationVersionError{Version: version}
 	}
 
-	applied, err := d.All()
+	applied, err := allContext(ctx, d)
 
 	if err != nil {
 		return err
 	}
 
-	if version, removed := wasRemovedMigration(applied, migrations); removed {
-		return RemovedMigrationError{Version: version}
+	if removed := removedVersions(applied, migrations); len(removed) > 0 {
+		if !ignoreUnknown {
+			return RemovedMigrationError{Version: removed[0]}
+		}
+
+		if logger != nil {
+			for _, version := range removed {
+				logger.Warn("ignoring unknown applied migration", "version", version)
+			}
+		}
 	}
 
 	if version, invalid := isInvalidChecksumMigration(applied, migrations); invalid {
@@ -225,8 +389,13 @@ func Validate(d Driver, migrations []Migration) error {
 
 // Info returns the status of all migrations
 func Info(d Driver, migrations []Migration) ([]MigrationInfo, error) {
+	return InfoContext(context.Background(), d, migrations)
+}
+
+// InfoContext is the context-aware variant of Info.
+func InfoContext(ctx context.Context, d Driver, migrations []Migration) ([]MigrationInfo, error) {
 	var info []MigrationInfo
-	records, err := d.All()
+	records, err := allContext(ctx, d)
 
 	if err != nil {
 		return info, err
@@ -245,91 +414,314 @@ func Info(d Driver, migrations []Migration) ([]MigrationInfo, error) {
 	return info, nil
 }
 
+// getStatus reports migration as Applied if its version is present in
+// inDatabase, and Pending otherwise.
 func getStatus(inDatabase []MigrationRecord, migration Migration) Status {
-	last := inDatabase[0]
-
-	// Check Pending
-	if migration.Version > last.Version {
-		return Pending
-	}
-
-	// Check Ignored
-	found := false
-
 	for _, record := range inDatabase {
 		if record.Version == migration.Version {
-			found = true
+			return Applied
 		}
 	}
 
-	if !found {
-		return Ignored
-	}
+	return Pending
+}
 
-	return Applied
+// defaultLockTimeout is the LockTimeout used by Migrate/MigrateWithOptions
+// when MigrateOptions.LockTimeout is left at its zero value, matching
+// golang-migrate's default.
+const defaultLockTimeout = 15 * time.Second
+
+// ErrLockTimeout is returned by Migrate when the database migration lock
+// could not be acquired within MigrateOptions.LockTimeout.
+var ErrLockTimeout = errors.New("darwin: timed out waiting to acquire the migration lock")
+
+// MigrateOptions configures the optional behavior of MigrateWithOptions
+// and MigrateContext.
+type MigrateOptions struct {
+	// LockTimeout bounds how long Migrate waits to acquire the database
+	// migration lock before giving up with ErrLockTimeout. Defaults to
+	// 15 seconds.
+	LockTimeout time.Duration
+	// StatementTimeout, when set, bounds how long each individual
+	// migration script is allowed to run.
+	StatementTimeout time.Duration
+	// Logger, when set, is called around each migration's Exec with its
+	// version, description and, on success, elapsed duration.
+	Logger Logger
+	// IgnoreUnknown, when true, downgrades an applied migration whose
+	// version is missing from migrations from a hard RemovedMigrationError
+	// to a warning logged through Logger, letting environments with
+	// feature-branch migrations merge back without failing validation.
+	IgnoreUnknown bool
 }
 
 // Migrate executes the missing migrations in database.
 func Migrate(d Driver, migrations []Migration) error {
+	return MigrateContext(context.Background(), d, migrations, MigrateOptions{})
+}
+
+// MigrateWithOptions executes the missing migrations in database, like
+// Migrate, while honoring the behavior configured in opts.
+func MigrateWithOptions(d Driver, migrations []Migration, opts MigrateOptions) error {
+	return MigrateContext(context.Background(), d, migrations, opts)
+}
+
+// MigrateContext is the context-aware variant of Migrate: ctx bounds the
+// whole call, including acquiring the migration lock, and opts.StatementTimeout
+// additionally bounds each individual migration script.
+func MigrateContext(ctx context.Context, d Driver, migrations []Migration, opts MigrateOptions) error {
+	return migrate(ctx, d, migrations, opts, nil)
+}
+
+// MigrateWithProgress behaves like MigrateContext, but instead of
+// blocking until every migration is done it streams a MigrationInfo to
+// the returned channel for each planned migration as it moves from
+// Pending to Applied or Error. The error channel receives the final
+// result (nil on success); both channels are closed once Migrate
+// returns.
+func MigrateWithProgress(ctx context.Context, d Driver, migrations []Migration, opts MigrateOptions) (<-chan MigrationInfo, <-chan error) {
+	progress := make(chan MigrationInfo)
+	done := make(chan error, 1)
+
+	go func() {
+		defer close(progress)
+		defer close(done)
+
+		done <- migrate(ctx, d, migrations, opts, progress)
+	}()
+
+	return progress, done
+}
+
+// sendProgress sends info on progress, if non-nil, giving up once ctx is
+// done instead of blocking forever. progress is unbuffered, so a
+// consumer that stops draining it (e.g. only watches the error channel)
+// would otherwise park migrate() on the send permanently; since migrate
+// releases the package mutex and the database lock only via defer on
+// return, that would deadlock every later Migrate call in the process.
+func sendProgress(ctx context.Context, progress chan<- MigrationInfo, info MigrationInfo) {
+	if progress == nil {
+		return
+	}
+
+	select {
+	case progress <- info:
+	case <-ctx.Done():
+	}
+}
+
+// migrate implements Migrate/MigrateContext/MigrateWithProgress. When
+// progress is non-nil, a MigrationInfo is sent to it for every planned
+// migration as it transitions from Pending to Applied or Error.
+func migrate(ctx context.Context, d Driver, migrations []Migration, opts MigrateOptions, progress chan<- MigrationInfo) error {
 	mutex.Lock()
 	defer mutex.Unlock()
 
-	err := d.Create()
+	lockTimeout := opts.LockTimeout
+	if lockTimeout <= 0 {
+		lockTimeout = defaultLockTimeout
+	}
+
+	lockCtx, cancel := context.WithTimeout(ctx, lockTimeout)
+	defer cancel()
+
+	if err := d.Lock(lockCtx); err != nil {
+		return err
+	}
+	defer d.Unlock(context.Background())
+
+	err := createContext(ctx, d)
 
 	if err != nil {
 		return err
 	}
 
-	err = Validate(d, migrations)
+	err = validate(ctx, d, migrations, opts.IgnoreUnknown, opts.Logger)
 
 	if err != nil {
 		return err
 	}
 
-	planned, err := planMigration(d, migrations)
+	planned, err := planMigrationContext(ctx, d, migrations)
 
 	if err != nil {
 		return err
 	}
 
 	for _, migration := range planned {
-		dur, err := d.Exec(migration.Script)
+		sendProgress(ctx, progress, MigrationInfo{Status: Pending, Migration: migration})
+
+		execCtx := ctx
+		if opts.StatementTimeout > 0 {
+			var cancel context.CancelFunc
+			execCtx, cancel = context.WithTimeout(ctx, opts.StatementTimeout)
+			defer cancel()
+		}
+
+		if opts.Logger != nil {
+			opts.Logger.Info("applying migration", "version", migration.Version, "description", migration.Description)
+		}
+
+		dur, err := runMigrationUp(execCtx, d, migration)
 
 		if err != nil {
+			if opts.Logger != nil {
+				opts.Logger.Error("migration failed", "version", migration.Version, "description", migration.Description, "error", err)
+			}
+			sendProgress(ctx, progress, MigrationInfo{Status: Error, Error: err, Migration: migration})
 			return err
 		}
 
-		err = d.Insert(MigrationRecord{
+		err = insertContext(ctx, d, MigrationRecord{
 			Version:       migration.Version,
 			Description:   migration.Description,
 			Checksum:      migration.Checksum(),
 			AppliedAt:     time.Now(),
-			ExecutionTime: dur,
+			ExecutionTime: dur.Seconds(),
 		})
 
 		if err != nil {
+			sendProgress(ctx, progress, MigrationInfo{Status: Error, Error: err, Migration: migration})
 			return err
 		}
 
+		if opts.Logger != nil {
+			opts.Logger.Info("applied migration", "version", migration.Version, "description", migration.Description, "elapsed", dur)
+		}
+
+		sendProgress(ctx, progress, MigrationInfo{Status: Applied, Migration: migration})
 	}
 
 	return nil
 }
 
-func wasRemovedMigration(applied []MigrationRecord, migrations []Migration) (float64, bool) {
+// Rollback reverts every applied migration with a version greater than
+// targetVersion, running their down scripts from the highest version
+// down, inside the global mutex and the same database-level lock Migrate
+// takes, so a Rollback in one process can't race a Migrate/Rollback in
+// another. The order follows versions, not applied_at: migrations that
+// were applied out of version order are not reverted in the reverse of
+// the order they were applied in. Checksums are verified before a down script runs, just like
+// Migrate verifies them before applying.
+func Rollback(d Driver, migrations []Migration, targetVersion float64) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if err := lockForRollback(d); err != nil {
+		return err
+	}
+	defer d.Unlock(context.Background())
+
+	return rollback(d, migrations, targetVersion)
+}
+
+// RollbackLast reverts the n applied migrations with the highest
+// versions, running their down scripts from the highest version down
+// inside the global mutex and the same database-level lock Migrate takes.
+// When migrations were applied out of version order, these are not
+// necessarily the last n that were applied.
+func RollbackLast(d Driver, migrations []Migration, n int) error {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if n <= 0 {
+		return nil
+	}
+
+	if err := lockForRollback(d); err != nil {
+		return err
+	}
+	defer d.Unlock(context.Background())
+
+	records, err := d.All()
+	if err != nil {
+		return err
+	}
+
+	sort.Sort(sort.Reverse(byMigrationRecordVersion(records)))
+
+	if n >= len(records) {
+		return rollback(d, migrations, -1)
+	}
+
+	return rollback(d, migrations, records[n].Version)
+}
+
+// lockForRollback acquires d's database-level lock with the same
+// defaultLockTimeout migrate uses. Callers must hold the global mutex
+// and release the lock themselves once they're done.
+func lockForRollback(d Driver) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultLockTimeout)
+	defer cancel()
+
+	return d.Lock(ctx)
+}
+
+// rollback reverts every applied migration with a version greater than
+// targetVersion. Callers must hold the global mutex and d's
+// database-level lock.
+func rollback(d Driver, migrations []Migration, targetVersion float64) error {
+	if err := Validate(d, migrations); err != nil {
+		return err
+	}
+
+	records, err := d.All()
+	if err != nil {
+		return err
+	}
+
+	sort.Sort(sort.Reverse(byMigrationRecordVersion(records)))
+
+	migrationByVersion := map[float64]Migration{}
+	for _, migration := range migrations {
+		migrationByVersion[migration.Version] = migration
+	}
+
+	for _, record := range records {
+		if record.Version <= targetVersion {
+			break
+		}
+
+		migration, ok := migrationByVersion[record.Version]
+		if !ok {
+			return RemovedMigrationError{Version: record.Version}
+		}
+
+		if migration.Checksum() != record.Checksum {
+			return InvalidChecksumError{Version: record.Version}
+		}
+
+		if _, err := runMigrationDown(context.Background(), d, migration); err != nil {
+			return err
+		}
+
+		if err := d.Delete(record.Version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removedVersions returns the versions present in applied but missing
+// from migrations, i.e. migrations that were applied to the database but
+// have since disappeared from the source.
+func removedVersions(applied []MigrationRecord, migrations []Migration) []float64 {
 	versionMap := map[float64]Migration{}
 
 	for _, migration := range migrations {
 		versionMap[migration.Version] = migration
 	}
 
+	var removed []float64
+
 	for _, migration := range applied {
 		if _, ok := versionMap[migration.Version]; !ok {
-			return migration.Version, true
+			removed = append(removed, migration.Version)
 		}
 	}
 
-	return 0, false
+	return removed
 }
 
 func isInvalidChecksumMigration(applied []MigrationRecord, migrations []Migration) (float64, bool) {
@@ -378,29 +770,27 @@ func isDuplicated(migrations []Migration) (float64, bool) {
 	return 0, false
 }
 
-func planMigration(d Driver, migrations []Migration) ([]Migration, error) {
-	records, err := d.All()
+// planMigrationContext returns every source migration whose version is
+// not already in the applied set, regardless of whether it sorts before
+// or after the highest applied version. This lets feature branches merge
+// their migrations back in any order instead of only appending at the
+// end.
+func planMigrationContext(ctx context.Context, d Driver, migrations []Migration) ([]Migration, error) {
+	records, err := allContext(ctx, d)
 
 	if err != nil {
 		return []Migration{}, err
 	}
 
-	// Apply all migrations
-	if len(records) == 0 {
-		return migrations, nil
+	applied := map[float64]bool{}
+	for _, record := range records {
+		applied[record.Version] = true
 	}
 
-	// Which migrations needs to be applied
 	var planned []Migration
 
-	// Make sure the order is correct
-	// Do not trust the driver.
-	sort.Sort(sort.Reverse(byMigrationRecordVersion(records)))
-	last := records[0]
-
-	// Apply all migrations that are greater than the last migration
 	for _, migration := range migrations {
-		if migration.Version > last.Version {
+		if !applied[migration.Version] {
 			planned = append(planned, migration)
 		}
 	}
@@ -416,3 +806,9 @@ type byMigrationVersion []Migration
 func (b byMigrationVersion) Len() int           { return len(b) }
 func (b byMigrationVersion) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
 func (b byMigrationVersion) Less(i, j int) bool { return b[i].Version < b[j].Version }
+
+type byMigrationRecordVersion []MigrationRecord
+
+func (b byMigrationRecordVersion) Len() int           { return len(b) }
+func (b byMigrationRecordVersion) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byMigrationRecordVersion) Less(i, j int) bool { return b[i].Version < b[j].Version }
@@ -0,0 +1,250 @@
+package darwin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDriver is a minimal in-memory Driver used to exercise the pure
+// planning/selection logic in this package without a real database.
+type fakeDriver struct {
+	records []MigrationRecord
+	execLog []string
+	deleted []float64
+}
+
+func (f *fakeDriver) Create() error { return nil }
+
+func (f *fakeDriver) All() ([]MigrationRecord, error) {
+	records := make([]MigrationRecord, len(f.records))
+	copy(records, f.records)
+	return records, nil
+}
+
+func (f *fakeDriver) Exec(script string) (time.Duration, error) {
+	f.execLog = append(f.execLog, script)
+	return 0, nil
+}
+
+func (f *fakeDriver) Insert(record MigrationRecord) error {
+	f.records = append(f.records, record)
+	return nil
+}
+
+func (f *fakeDriver) Delete(version float64) error {
+	f.deleted = append(f.deleted, version)
+
+	for i, r := range f.records {
+		if r.Version == version {
+			f.records = append(f.records[:i], f.records[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+func (f *fakeDriver) Lock(ctx context.Context) error   { return nil }
+func (f *fakeDriver) Unlock(ctx context.Context) error { return nil }
+
+func appliedMigration(version float64, downScript string) (Migration, MigrationRecord) {
+	m := Migration{Version: version, Script: "up", DownScript: downScript}
+	return m, MigrationRecord{Version: version, Checksum: m.Checksum()}
+}
+
+func TestRollbackTargetSelection(t *testing.T) {
+	m1, r1 := appliedMigration(1, "down 1")
+	m2, r2 := appliedMigration(2, "down 2")
+	m3, r3 := appliedMigration(3, "down 3")
+
+	driver := &fakeDriver{records: []MigrationRecord{r1, r2, r3}}
+	migrations := []Migration{m1, m2, m3}
+
+	if err := Rollback(driver, migrations, 1); err != nil {
+		t.Fatalf("Rollback returned error: %v", err)
+	}
+
+	if got, want := driver.deleted, []float64{3, 2}; !equalFloats(got, want) {
+		t.Fatalf("deleted versions = %v, want %v", got, want)
+	}
+
+	if got, want := driver.execLog, []string{"down 3", "down 2"}; !equalStrings(got, want) {
+		t.Fatalf("down scripts run = %v, want %v", got, want)
+	}
+}
+
+func TestRollbackLastPicksTargetFromAppliedRecords(t *testing.T) {
+	m1, r1 := appliedMigration(1, "down 1")
+	m2, r2 := appliedMigration(2, "down 2")
+	m3, r3 := appliedMigration(3, "down 3")
+
+	driver := &fakeDriver{records: []MigrationRecord{r1, r2, r3}}
+	migrations := []Migration{m1, m2, m3}
+
+	if err := RollbackLast(driver, migrations, 1); err != nil {
+		t.Fatalf("RollbackLast returned error: %v", err)
+	}
+
+	if got, want := driver.deleted, []float64{3}; !equalFloats(got, want) {
+		t.Fatalf("deleted versions = %v, want %v", got, want)
+	}
+}
+
+func TestRollbackLastBeyondAppliedCountRevertsEverything(t *testing.T) {
+	m1, r1 := appliedMigration(1, "down 1")
+	m2, r2 := appliedMigration(2, "down 2")
+
+	driver := &fakeDriver{records: []MigrationRecord{r1, r2}}
+	migrations := []Migration{m1, m2}
+
+	if err := RollbackLast(driver, migrations, 10); err != nil {
+		t.Fatalf("RollbackLast returned error: %v", err)
+	}
+
+	if got, want := driver.deleted, []float64{2, 1}; !equalFloats(got, want) {
+		t.Fatalf("deleted versions = %v, want %v", got, want)
+	}
+}
+
+func TestRollbackRejectsMigrationWithNoDownScript(t *testing.T) {
+	m, r := appliedMigration(1, "")
+
+	driver := &fakeDriver{records: []MigrationRecord{r}}
+
+	err := Rollback(driver, []Migration{m}, 0)
+
+	if _, ok := err.(NoDownScriptError); !ok {
+		t.Fatalf("err = %v, want NoDownScriptError", err)
+	}
+
+	if len(driver.deleted) != 0 {
+		t.Fatalf("deleted = %v, want no deletions once the down script is rejected", driver.deleted)
+	}
+}
+
+func TestChecksumOverrideIsHashedInsteadOfScript(t *testing.T) {
+	a := Migration{Script: "unrelated script a", ChecksumOverride: "go-migration-1"}
+	b := Migration{Script: "unrelated script b", ChecksumOverride: "go-migration-1"}
+
+	if a.Checksum() != b.Checksum() {
+		t.Fatalf("checksums differ despite matching ChecksumOverride: %s != %s", a.Checksum(), b.Checksum())
+	}
+
+	c := Migration{Script: "go-migration-1"}
+
+	if a.Checksum() != c.Checksum() {
+		t.Fatalf("ChecksumOverride should hash the same as a Script with the same content")
+	}
+
+	d := Migration{Script: "unrelated script a"}
+
+	if a.Checksum() == d.Checksum() {
+		t.Fatalf("ChecksumOverride changed the checksum but comparing against the plain Script still matched")
+	}
+}
+
+func TestParseMigrationFileTimestampFilename(t *testing.T) {
+	contents := "SELECT 1;\n-- Direction: Down\nDROP TABLE x;\n"
+
+	migrations := parseMigrationFile("20240115093000_add_users.sql", contents)
+
+	if len(migrations) != 1 {
+		t.Fatalf("got %d migrations, want 1", len(migrations))
+	}
+
+	m := migrations[0]
+
+	if m.Version != 20240115093000 {
+		t.Fatalf("Version = %f, want 20240115093000", m.Version)
+	}
+
+	if m.Description != "add users" {
+		t.Fatalf("Description = %q, want %q", m.Description, "add users")
+	}
+
+	if !strings.Contains(m.Script, "SELECT 1;") {
+		t.Fatalf("Script = %q, want it to contain the up statement", m.Script)
+	}
+
+	if !strings.Contains(m.DownScript, "DROP TABLE x;") {
+		t.Fatalf("DownScript = %q, want it to contain the down statement", m.DownScript)
+	}
+}
+
+func TestParseMigrationFileFallsBackForNonTimestampNames(t *testing.T) {
+	contents := "--Version: 1\n--Description: plain\nSELECT 1;\n"
+
+	migrations := parseMigrationFile("schema.sql", contents)
+
+	if len(migrations) != 1 {
+		t.Fatalf("got %d migrations, want 1", len(migrations))
+	}
+
+	if migrations[0].Version != 1 {
+		t.Fatalf("Version = %f, want 1", migrations[0].Version)
+	}
+}
+
+func TestValidateIgnoreUnknownDowngradesRemovedMigrationError(t *testing.T) {
+	m, r := appliedMigration(1, "down 1")
+	removedRecord := MigrationRecord{Version: 2, Checksum: "stale"}
+
+	driver := &fakeDriver{records: []MigrationRecord{r, removedRecord}}
+
+	err := validate(context.Background(), driver, []Migration{m}, false, nil)
+	if _, ok := err.(RemovedMigrationError); !ok {
+		t.Fatalf("err = %v, want RemovedMigrationError when IgnoreUnknown is false", err)
+	}
+
+	err = validate(context.Background(), driver, []Migration{m}, true, nil)
+	if err != nil {
+		t.Fatalf("err = %v, want nil when IgnoreUnknown is true", err)
+	}
+}
+
+func TestPlanMigrationContextMergesOutOfOrderMigrations(t *testing.T) {
+	_, appliedHigh := appliedMigration(2, "down 2")
+	lower, _ := appliedMigration(1, "down 1")
+	higher, _ := appliedMigration(2, "down 2")
+
+	driver := &fakeDriver{records: []MigrationRecord{appliedHigh}}
+
+	planned, err := planMigrationContext(context.Background(), driver, []Migration{higher, lower})
+	if err != nil {
+		t.Fatalf("planMigrationContext returned error: %v", err)
+	}
+
+	if len(planned) != 1 || planned[0].Version != 1 {
+		t.Fatalf("planned = %+v, want only version 1 even though it sorts before the already applied version 2", planned)
+	}
+}
+
+func equalFloats(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,60 @@
+package darwin
+
+import "fmt"
+
+// DialectOptions configures the table used by a Dialect to keep track of
+// applied migrations. The zero value keeps the historical "db_version"
+// table name with no schema qualifier.
+type DialectOptions struct {
+	// TableName overrides the default "db_version" table name.
+	TableName string
+	// SchemaName, when set, qualifies TableName with "SchemaName.TableName".
+	SchemaName string
+}
+
+// tableName returns the, possibly schema-qualified, table name to use.
+func (o DialectOptions) tableName() string {
+	name := o.TableName
+	if name == "" {
+		name = "db_version"
+	}
+
+	if o.SchemaName != "" {
+		return o.SchemaName + "." + name
+	}
+
+	return name
+}
+
+// DialectFactory builds a Dialect configured with the given DialectOptions.
+type DialectFactory func(DialectOptions) Dialect
+
+// dialects holds the registry of Dialect implementations known by name,
+// populated by the Dialect implementations Darwin ships and extensible
+// through RegisterDialect.
+var dialects = map[string]DialectFactory{
+	"mysql":      func(o DialectOptions) Dialect { return MySQLDialect{Options: o} },
+	"postgres":   func(o DialectOptions) Dialect { return PostgresDialect{Options: o} },
+	"sqlite3":    func(o DialectOptions) Dialect { return SQLite3Dialect{Options: o} },
+	"sqlserver":  func(o DialectOptions) Dialect { return SQLServerDialect{Options: o} },
+	"clickhouse": func(o DialectOptions) Dialect { return ClickHouseDialect{Options: o} },
+}
+
+// RegisterDialect makes a Dialect available under name, so that NewDialect
+// can build it. It is meant to let callers plug in dialects Darwin does
+// not ship out of the box; registering an existing name overrides it.
+func RegisterDialect(name string, factory DialectFactory) {
+	dialects[name] = factory
+}
+
+// NewDialect builds the Dialect registered under name with the given
+// options. Use the package level dialect structs directly when no
+// registry indirection is needed.
+func NewDialect(name string, options DialectOptions) (Dialect, error) {
+	factory, ok := dialects[name]
+	if !ok {
+		return nil, fmt.Errorf("darwin: no dialect registered for %q", name)
+	}
+
+	return factory(options), nil
+}
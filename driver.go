@@ -0,0 +1,366 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Driver is the interface implemented by the database drivers supported by
+// Darwin. GenericDriver implements it on top of database/sql for any
+// database that has a Dialect; custom drivers can implement it directly.
+type Driver interface {
+	Create() error
+	All() ([]MigrationRecord, error)
+	Exec(string) (time.Duration, error)
+	Insert(MigrationRecord) error
+	Delete(version float64) error
+
+	// Lock acquires a database-level lock so that only one process at a
+	// time can plan and run migrations, it must block until the lock is
+	// acquired or ctx is done. Drivers without a locking implementation
+	// should treat it as a no-op.
+	Lock(ctx context.Context) error
+	// Unlock releases a lock acquired by Lock.
+	Unlock(ctx context.Context) error
+}
+
+// MigrationRecord is the representation of a Migration already applied
+// to the database.
+type MigrationRecord struct {
+	Version       float64
+	Description   string
+	Checksum      string
+	AppliedAt     time.Time
+	ExecutionTime float64
+}
+
+// Dialect translates the operations needed by GenericDriver into SQL
+// statements for a specific database.
+type Dialect interface {
+	CreateTableSQL() string
+	InsertSQL() string
+	AllSQL() string
+	DeleteSQL() string
+}
+
+// NativeTimeDialect is implemented by Dialects whose applied_at column is
+// a genuine timestamp type, such as PostgresDialect's TIMESTAMPTZ or
+// ClickHouseDialect's DateTime64(3), instead of the integer Unix
+// timestamp MySQLDialect, SQLite3Dialect and SQLServerDialect use.
+// GenericDriver binds and scans AppliedAt as a time.Time for these
+// dialects instead of int64 Unix seconds, matching each column's actual
+// type.
+type NativeTimeDialect interface {
+	Dialect
+	nativeAppliedAt()
+}
+
+// Locker is implemented by Dialects that support acquiring a
+// database-level lock, letting GenericDriver serialize concurrent
+// Migrate calls across processes instead of only within one.
+type Locker interface {
+	LockSQL() string
+	UnlockSQL() string
+}
+
+// LockChecker is implemented by Locker Dialects whose LockSQL reports
+// whether the lock was actually acquired through its result row instead
+// of only through a query error, such as MySQL's GET_LOCK, which returns
+// 1, 0 or NULL rather than failing the query. GenericDriver.Lock uses it
+// to tell a "lock not acquired" result apart from success.
+type LockChecker interface {
+	Locker
+	CheckLockResult(row *sql.Row) error
+}
+
+// PollingLocker is implemented by Locker Dialects whose LockSQL does not
+// block server-side until the lock is free, such as SQLite3Dialect's
+// row-insert based lock, which instead fails immediately on contention.
+// GenericDriver.Lock retries LockSQL on this interval until it succeeds,
+// ctx is done, or lockCtx's deadline passes.
+type PollingLocker interface {
+	Locker
+	LockPollInterval() time.Duration
+}
+
+// DriverContext is implemented by drivers that support per-call context
+// cancellation and deadlines. MigrateContext, ValidateContext and
+// InfoContext use it when a Driver implements it, and fall back to the
+// plain Driver methods otherwise.
+type DriverContext interface {
+	Driver
+
+	CreateContext(ctx context.Context) error
+	AllContext(ctx context.Context) ([]MigrationRecord, error)
+	ExecContext(ctx context.Context, script string) (time.Duration, error)
+	InsertContext(ctx context.Context, record MigrationRecord) error
+}
+
+// createContext calls d.CreateContext when d implements DriverContext,
+// and falls back to d.Create otherwise.
+func createContext(ctx context.Context, d Driver) error {
+	if dc, ok := d.(DriverContext); ok {
+		return dc.CreateContext(ctx)
+	}
+
+	return d.Create()
+}
+
+// allContext calls d.AllContext when d implements DriverContext, and
+// falls back to d.All otherwise.
+func allContext(ctx context.Context, d Driver) ([]MigrationRecord, error) {
+	if dc, ok := d.(DriverContext); ok {
+		return dc.AllContext(ctx)
+	}
+
+	return d.All()
+}
+
+// execContext calls d.ExecContext when d implements DriverContext, and
+// falls back to d.Exec otherwise.
+func execContext(ctx context.Context, d Driver, script string) (time.Duration, error) {
+	if dc, ok := d.(DriverContext); ok {
+		return dc.ExecContext(ctx, script)
+	}
+
+	return d.Exec(script)
+}
+
+// insertContext calls d.InsertContext when d implements DriverContext,
+// and falls back to d.Insert otherwise.
+func insertContext(ctx context.Context, d Driver, record MigrationRecord) error {
+	if dc, ok := d.(DriverContext); ok {
+		return dc.InsertContext(ctx, record)
+	}
+
+	return d.Insert(record)
+}
+
+// lockConn holds the single physical connection pinned for the lifetime
+// of a database-level lock. Session-scoped locks such as Postgres's
+// advisory locks or MySQL's GET_LOCK are tied to the connection that
+// acquired them, so Lock and Unlock must run against the same *sql.Conn
+// rather than two connections pulled independently from the pool.
+// GenericDriver stores a pointer to one so every copy of the value
+// shares it.
+type lockConn struct {
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+// GenericDriver is a Driver implementation built on top of database/sql,
+// it delegates every dialect specific statement to a Dialect.
+type GenericDriver struct {
+	DB      *sql.DB
+	Dialect Dialect
+	lock    *lockConn
+}
+
+// NewGenericDriver returns a GenericDriver for the given database
+// connection and Dialect.
+func NewGenericDriver(db *sql.DB, dialect Dialect) GenericDriver {
+	return GenericDriver{
+		DB:      db,
+		Dialect: dialect,
+		lock:    &lockConn{},
+	}
+}
+
+// Create creates the table used to keep track of the applied migrations.
+func (driver GenericDriver) Create() error {
+	return driver.CreateContext(context.Background())
+}
+
+// CreateContext is the context-aware variant of Create.
+func (driver GenericDriver) CreateContext(ctx context.Context) error {
+	_, err := driver.DB.ExecContext(ctx, driver.Dialect.CreateTableSQL())
+	return err
+}
+
+// All returns all migration records stored in the database.
+func (driver GenericDriver) All() ([]MigrationRecord, error) {
+	return driver.AllContext(context.Background())
+}
+
+// AllContext is the context-aware variant of All.
+func (driver GenericDriver) AllContext(ctx context.Context) ([]MigrationRecord, error) {
+	var records []MigrationRecord
+
+	rows, err := driver.DB.QueryContext(ctx, driver.Dialect.AllSQL())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	_, nativeTime := driver.Dialect.(NativeTimeDialect)
+
+	for rows.Next() {
+		var record MigrationRecord
+		var appliedAtUnix int64
+
+		dest := []any{
+			&record.Version,
+			&record.Description,
+			&record.Checksum,
+			&record.AppliedAt,
+			&record.ExecutionTime,
+		}
+		if !nativeTime {
+			dest[3] = &appliedAtUnix
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		if !nativeTime {
+			record.AppliedAt = time.Unix(appliedAtUnix, 0)
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// Exec executes a migration script and returns how long it took to run.
+func (driver GenericDriver) Exec(script string) (time.Duration, error) {
+	return driver.ExecContext(context.Background(), script)
+}
+
+// ExecContext is the context-aware variant of Exec.
+func (driver GenericDriver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	start := time.Now()
+
+	_, err := driver.DB.ExecContext(ctx, script)
+
+	return time.Since(start), err
+}
+
+// Insert stores a migration record in the database.
+func (driver GenericDriver) Insert(record MigrationRecord) error {
+	return driver.InsertContext(context.Background(), record)
+}
+
+// InsertContext is the context-aware variant of Insert.
+func (driver GenericDriver) InsertContext(ctx context.Context, record MigrationRecord) error {
+	var appliedAt any = record.AppliedAt.Unix()
+	if _, ok := driver.Dialect.(NativeTimeDialect); ok {
+		appliedAt = record.AppliedAt
+	}
+
+	_, err := driver.DB.ExecContext(ctx,
+		driver.Dialect.InsertSQL(),
+		record.Version,
+		record.Description,
+		record.Checksum,
+		appliedAt,
+		record.ExecutionTime,
+	)
+
+	return err
+}
+
+// Delete removes a migration record from the database. It is used by
+// Rollback and RollbackLast to undo a previously applied migration.
+func (driver GenericDriver) Delete(version float64) error {
+	_, err := driver.DB.Exec(driver.Dialect.DeleteSQL(), version)
+	return err
+}
+
+// Lock acquires the Dialect's database-level lock, if it implements
+// Locker. Dialects without a Locker implementation have no way to
+// serialize migrations across processes, so Lock is a no-op for them.
+//
+// The lock is acquired on a single physical connection pinned for the
+// lock's lifetime and released by Unlock on that same connection, since
+// session-scoped locks such as Postgres's advisory locks or MySQL's
+// GET_LOCK are tied to the connection that issued them. A GenericDriver
+// built as a struct literal instead of through NewGenericDriver has no
+// place to keep that connection, so Lock and Unlock are no-ops for it.
+func (driver GenericDriver) Lock(ctx context.Context) error {
+	locker, ok := driver.Dialect.(Locker)
+	if !ok || driver.lock == nil {
+		return nil
+	}
+
+	conn, err := driver.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := acquireLock(ctx, conn, locker); err != nil {
+		conn.Close()
+		return err
+	}
+
+	driver.lock.mu.Lock()
+	driver.lock.conn = conn
+	driver.lock.mu.Unlock()
+
+	return nil
+}
+
+// acquireLock runs locker.LockSQL() on conn, polling on PollingLocker's
+// interval for dialects whose lock does not block server-side, and
+// inspecting the result row for LockChecker dialects whose LockSQL
+// reports success through its return value instead of a query error.
+func acquireLock(ctx context.Context, conn *sql.Conn, locker Locker) error {
+	poller, polls := locker.(PollingLocker)
+
+	for {
+		err := runLockSQL(ctx, conn, locker)
+		if err == nil {
+			return nil
+		}
+
+		if !polls {
+			if ctx.Err() == context.DeadlineExceeded {
+				return ErrLockTimeout
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrLockTimeout
+		case <-time.After(poller.LockPollInterval()):
+		}
+	}
+}
+
+// runLockSQL executes locker.LockSQL() once and reports whether the lock
+// was acquired, using locker's CheckLockResult when it implements
+// LockChecker to read a success flag out of the result row.
+func runLockSQL(ctx context.Context, conn *sql.Conn, locker Locker) error {
+	if checker, ok := locker.(LockChecker); ok {
+		return checker.CheckLockResult(conn.QueryRowContext(ctx, locker.LockSQL()))
+	}
+
+	_, err := conn.ExecContext(ctx, locker.LockSQL())
+	return err
+}
+
+// Unlock releases a lock acquired by Lock, on the same connection Lock
+// pinned, and returns that connection to the pool.
+func (driver GenericDriver) Unlock(ctx context.Context) error {
+	locker, ok := driver.Dialect.(Locker)
+	if !ok || driver.lock == nil {
+		return nil
+	}
+
+	driver.lock.mu.Lock()
+	conn := driver.lock.conn
+	driver.lock.conn = nil
+	driver.lock.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	defer conn.Close()
+
+	_, err := conn.ExecContext(ctx, locker.UnlockSQL())
+	return err
+}
@@ -0,0 +1,99 @@
+package darwin
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// contextFakeDriver wraps fakeDriver and additionally implements
+// DriverContext, recording whether each *Context method was actually
+// called so tests can tell it apart from the fallback path.
+type contextFakeDriver struct {
+	*fakeDriver
+
+	createCalled bool
+	allCalled    bool
+	execCalled   bool
+	insertCalled bool
+}
+
+func (c *contextFakeDriver) CreateContext(ctx context.Context) error {
+	c.createCalled = true
+	return c.fakeDriver.Create()
+}
+
+func (c *contextFakeDriver) AllContext(ctx context.Context) ([]MigrationRecord, error) {
+	c.allCalled = true
+	return c.fakeDriver.All()
+}
+
+func (c *contextFakeDriver) ExecContext(ctx context.Context, script string) (time.Duration, error) {
+	c.execCalled = true
+	return c.fakeDriver.Exec(script)
+}
+
+func (c *contextFakeDriver) InsertContext(ctx context.Context, record MigrationRecord) error {
+	c.insertCalled = true
+	return c.fakeDriver.Insert(record)
+}
+
+func TestContextDispatchPrefersDriverContextMethods(t *testing.T) {
+	cd := &contextFakeDriver{fakeDriver: &fakeDriver{}}
+	ctx := context.Background()
+
+	if err := createContext(ctx, cd); err != nil {
+		t.Fatalf("createContext() = %v", err)
+	}
+	if !cd.createCalled {
+		t.Error("createContext did not call CreateContext on a DriverContext driver")
+	}
+
+	if _, err := allContext(ctx, cd); err != nil {
+		t.Fatalf("allContext() = %v", err)
+	}
+	if !cd.allCalled {
+		t.Error("allContext did not call AllContext on a DriverContext driver")
+	}
+
+	if _, err := execContext(ctx, cd, "script"); err != nil {
+		t.Fatalf("execContext() = %v", err)
+	}
+	if !cd.execCalled {
+		t.Error("execContext did not call ExecContext on a DriverContext driver")
+	}
+
+	if err := insertContext(ctx, cd, MigrationRecord{Version: 1}); err != nil {
+		t.Fatalf("insertContext() = %v", err)
+	}
+	if !cd.insertCalled {
+		t.Error("insertContext did not call InsertContext on a DriverContext driver")
+	}
+}
+
+func TestContextDispatchFallsBackWithoutDriverContext(t *testing.T) {
+	plain := &fakeDriver{}
+	ctx := context.Background()
+
+	if err := createContext(ctx, plain); err != nil {
+		t.Fatalf("createContext() = %v", err)
+	}
+
+	if _, err := allContext(ctx, plain); err != nil {
+		t.Fatalf("allContext() = %v", err)
+	}
+
+	if _, err := execContext(ctx, plain, "script"); err != nil {
+		t.Fatalf("execContext() = %v", err)
+	}
+	if len(plain.execLog) != 1 || plain.execLog[0] != "script" {
+		t.Errorf("execLog = %v, want [script]", plain.execLog)
+	}
+
+	if err := insertContext(ctx, plain, MigrationRecord{Version: 1}); err != nil {
+		t.Fatalf("insertContext() = %v", err)
+	}
+	if len(plain.records) != 1 || plain.records[0].Version != 1 {
+		t.Errorf("records = %v, want a single record with version 1", plain.records)
+	}
+}
@@ -0,0 +1,67 @@
+package darwin
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+)
+
+// TestGenericDriverLockUsesLockCheckerResult exercises acquireLock's
+// LockChecker dispatch against MySQLDialect's GET_LOCK, which reports
+// whether the lock was acquired through its result row rather than a
+// query error.
+func TestGenericDriverLockUsesLockCheckerResult(t *testing.T) {
+	cases := []struct {
+		name    string
+		lockVal driver.Value
+		wantErr error
+	}{
+		{name: "acquired", lockVal: int64(1), wantErr: nil},
+		{name: "timed out", lockVal: int64(0), wantErr: ErrLockTimeout},
+		{name: "error", lockVal: nil, wantErr: ErrLockTimeout},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &stubDB{lockResult: tc.lockVal}
+			gd := NewGenericDriver(openStubDB(t, db), MySQLDialect{})
+
+			err := gd.Lock(context.Background())
+			if err != tc.wantErr {
+				t.Fatalf("Lock() = %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// TestGenericDriverLockRetriesPollingLocker exercises acquireLock's
+// PollingLocker dispatch against SQLite3Dialect's row-insert lock, which
+// fails immediately on contention instead of blocking server-side.
+func TestGenericDriverLockRetriesPollingLocker(t *testing.T) {
+	db := &stubDB{failLockAttempts: 2}
+	gd := NewGenericDriver(openStubDB(t, db), SQLite3Dialect{})
+
+	if err := gd.Lock(context.Background()); err != nil {
+		t.Fatalf("Lock() = %v, want nil after retrying through contention", err)
+	}
+
+	if db.lockAttempts != 3 {
+		t.Fatalf("lockAttempts = %d, want 3 (2 failures + 1 success)", db.lockAttempts)
+	}
+}
+
+// TestGenericDriverLockGivesUpWhenContextExpires verifies a
+// PollingLocker dialect's retry loop stops once ctx is done instead of
+// polling forever.
+func TestGenericDriverLockGivesUpWhenContextExpires(t *testing.T) {
+	db := &stubDB{failLockAttempts: 1000}
+	gd := NewGenericDriver(openStubDB(t, db), SQLite3Dialect{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := gd.Lock(ctx); err != ErrLockTimeout {
+		t.Fatalf("Lock() = %v, want ErrLockTimeout", err)
+	}
+}
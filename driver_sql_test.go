@@ -0,0 +1,81 @@
+package darwin
+
+import (
+	"testing"
+	"time"
+)
+
+// TestGenericDriverAppliedAtRoundTrip runs Create/Insert/All through a
+// stub database/sql/driver instead of fakeDriver, so the int64/time.Time
+// wire format InsertContext binds and AllContext scans is exercised by
+// database/sql's real conversion rules rather than assumed.
+func TestGenericDriverAppliedAtRoundTrip(t *testing.T) {
+	db := &stubDB{}
+	driver := NewGenericDriver(openStubDB(t, db), MySQLDialect{})
+
+	if err := driver.Create(); err != nil {
+		t.Fatalf("Create() = %v", err)
+	}
+
+	appliedAt := time.Unix(1700000000, 0).UTC()
+	record := MigrationRecord{
+		Version:       1,
+		Description:   "create users table",
+		Checksum:      "abc123",
+		AppliedAt:     appliedAt,
+		ExecutionTime: 1.5,
+	}
+
+	if err := driver.Insert(record); err != nil {
+		t.Fatalf("Insert() = %v", err)
+	}
+
+	got, err := driver.All()
+	if err != nil {
+		t.Fatalf("All() = %v", err)
+	}
+
+	if len(got) != 1 {
+		t.Fatalf("All() returned %d records, want 1", len(got))
+	}
+
+	if !got[0].AppliedAt.Equal(appliedAt) {
+		t.Errorf("AppliedAt = %v, want %v", got[0].AppliedAt, appliedAt)
+	}
+
+	if got[0].Version != record.Version || got[0].Description != record.Description ||
+		got[0].Checksum != record.Checksum || got[0].ExecutionTime != record.ExecutionTime {
+		t.Errorf("All()[0] = %+v, want %+v", got[0], record)
+	}
+}
+
+// fakeNativeTimeDialect is a minimal NativeTimeDialect used to verify
+// InsertContext/AllContext bind and scan AppliedAt as a time.Time instead
+// of a Unix integer when the Dialect's applied_at column is a genuine
+// timestamp type, such as PostgresDialect's or ClickHouseDialect's.
+type fakeNativeTimeDialect struct {
+	MySQLDialect
+}
+
+func (fakeNativeTimeDialect) nativeAppliedAt() {}
+
+func TestGenericDriverAppliedAtRoundTripNativeTimeDialect(t *testing.T) {
+	db := &stubDB{}
+	driver := NewGenericDriver(openStubDB(t, db), fakeNativeTimeDialect{})
+
+	appliedAt := time.Date(2024, 3, 2, 15, 4, 5, 0, time.UTC)
+	record := MigrationRecord{Version: 1, Description: "init", Checksum: "abc", AppliedAt: appliedAt, ExecutionTime: 0.2}
+
+	if err := driver.Insert(record); err != nil {
+		t.Fatalf("Insert() = %v", err)
+	}
+
+	got, err := driver.All()
+	if err != nil {
+		t.Fatalf("All() = %v", err)
+	}
+
+	if len(got) != 1 || !got[0].AppliedAt.Equal(appliedAt) {
+		t.Fatalf("All() = %+v, want AppliedAt = %v", got, appliedAt)
+	}
+}
@@ -0,0 +1,89 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TxDriver is implemented by drivers that can run a Go migration's Up or
+// Down function inside a transaction, such as GenericDriver. Running a
+// Go migration against a Driver that does not implement it returns an
+// error; planning does not check for it.
+type TxDriver interface {
+	ExecTx(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) (time.Duration, error)
+}
+
+// ExecTx runs fn inside a transaction, committing on success and rolling
+// back on error, and returns how long it took to run.
+func (driver GenericDriver) ExecTx(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) (time.Duration, error) {
+	start := time.Now()
+
+	tx, err := driver.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := fn(ctx, tx); err != nil {
+		tx.Rollback()
+		return time.Since(start), err
+	}
+
+	return time.Since(start), tx.Commit()
+}
+
+// runMigrationUp applies migration, running its Up function inside a
+// transaction for Go migrations and its Script otherwise.
+func runMigrationUp(ctx context.Context, d Driver, migration Migration) (time.Duration, error) {
+	if migration.Up == nil {
+		return execContext(ctx, d, migration.Script)
+	}
+
+	txDriver, ok := d.(TxDriver)
+	if !ok {
+		return 0, fmt.Errorf("darwin: driver does not support the Go migration for version %f", migration.Version)
+	}
+
+	return txDriver.ExecTx(ctx, migration.Up)
+}
+
+// runMigrationDown reverts migration, running its Down function inside a
+// transaction for Go migrations and its DownScript otherwise. It refuses
+// to run when migration has neither: silently Exec-ing an empty script is
+// driver-dependent and would let the caller delete the db_version row
+// without actually undoing anything.
+func runMigrationDown(ctx context.Context, d Driver, migration Migration) (time.Duration, error) {
+	if migration.Down == nil && migration.DownScript == "" {
+		return 0, NoDownScriptError{Version: migration.Version}
+	}
+
+	if migration.Down == nil {
+		return d.Exec(migration.DownScript)
+	}
+
+	txDriver, ok := d.(TxDriver)
+	if !ok {
+		return 0, fmt.Errorf("darwin: driver does not support the Go migration for version %f", migration.Version)
+	}
+
+	return txDriver.ExecTx(ctx, migration.Down)
+}
+
+// RegisterGoMigration builds a Migration backed by Go functions instead
+// of a SQL script, for changes that can't be expressed in a static .sql
+// file: data backfills, calls out to other services, or DDL that depends
+// on runtime state. Since there is no script body to hash, checksum
+// should be a stable identifier for the migration's logic.
+//
+// The resulting Migration is merged with the ones returned by
+// ParseMigrationsDirFiles by appending it to the slice passed to New.
+func RegisterGoMigration(version float64, description, checksum string, up, down func(ctx context.Context, tx *sql.Tx) error) Migration {
+	return Migration{
+		Version:          version,
+		Description:      description,
+		ChecksumOverride: checksum,
+		Up:               up,
+		Down:             down,
+	}
+}
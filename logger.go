@@ -0,0 +1,36 @@
+package darwin
+
+import "log/slog"
+
+// Logger receives structured events from Migrate/MigrateWithProgress
+// around each migration's execution. Its shape matches *slog.Logger's
+// Info/Warn/Error methods, so a *slog.Logger can be used directly via
+// NewSlogLogger.
+type Logger interface {
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// slogLogger adapts a *slog.Logger to the Logger interface.
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger adapts logger to the Logger interface expected by
+// MigrateOptions.Logger.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	return slogLogger{logger: logger}
+}
+
+func (s slogLogger) Info(msg string, args ...any) {
+	s.logger.Info(msg, args...)
+}
+
+func (s slogLogger) Warn(msg string, args ...any) {
+	s.logger.Warn(msg, args...)
+}
+
+func (s slogLogger) Error(msg string, args ...any) {
+	s.logger.Error(msg, args...)
+}
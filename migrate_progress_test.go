@@ -0,0 +1,80 @@
+package darwin
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// fakeLogger is a minimal Logger that records the message of each call,
+// used to assert MigrateWithProgress logs around each migration without
+// depending on slog's output format.
+type fakeLogger struct {
+	infos  []string
+	warns  []string
+	errors []string
+}
+
+func (l *fakeLogger) Info(msg string, args ...any)  { l.infos = append(l.infos, msg) }
+func (l *fakeLogger) Warn(msg string, args ...any)  { l.warns = append(l.warns, msg) }
+func (l *fakeLogger) Error(msg string, args ...any) { l.errors = append(l.errors, msg) }
+
+func TestMigrateWithProgressStreamsStatusesAndLogs(t *testing.T) {
+	migration := Migration{Version: 1, Script: "CREATE TABLE x;"}
+	d := &fakeDriver{}
+	logger := &fakeLogger{}
+
+	progress, done := MigrateWithProgress(context.Background(), d, []Migration{migration}, MigrateOptions{Logger: logger})
+
+	var statuses []Status
+	for info := range progress {
+		statuses = append(statuses, info.Status)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("MigrateWithProgress() error = %v", err)
+	}
+
+	want := []Status{Pending, Applied}
+	if len(statuses) != len(want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", statuses, want)
+		}
+	}
+
+	if len(logger.infos) != 2 || logger.infos[0] != "applying migration" || logger.infos[1] != "applied migration" {
+		t.Errorf("logger.infos = %v, want [applying migration applied migration]", logger.infos)
+	}
+	if len(logger.errors) != 0 {
+		t.Errorf("logger.errors = %v, want none", logger.errors)
+	}
+}
+
+// TestMigrateWithProgressDoesNotBlockWhenConsumerStopsReading is a
+// regression test for sendProgress: a consumer that reads only the
+// first event and then stops draining progress must not deadlock
+// migrate(), since it only releases the global mutex and the database
+// lock once it returns.
+func TestMigrateWithProgressDoesNotBlockWhenConsumerStopsReading(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Script: "a"},
+		{Version: 2, Script: "b"},
+	}
+	d := &fakeDriver{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	progress, done := MigrateWithProgress(ctx, d, migrations, MigrateOptions{})
+
+	<-progress // read only the first event, then stop draining
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("MigrateWithProgress did not return once ctx expired; a stalled consumer deadlocked it")
+	}
+}
@@ -1,11 +1,18 @@
 package darwin
 
+import (
+	"database/sql"
+	"fmt"
+)
+
 // MySQLDialect a Dialect configured for MySQL
-type MySQLDialect struct{}
+type MySQLDialect struct {
+	Options DialectOptions
+}
 
 // CreateTableSQL returns the SQL to create the schema table
 func (m MySQLDialect) CreateTableSQL() string {
-	return `CREATE TABLE IF NOT EXISTS db_version
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
                 (
                     id             INT          auto_increment,
                     version        FLOAT        NOT NULL,
@@ -15,12 +22,12 @@ func (m MySQLDialect) CreateTableSQL() string {
                     execution_time FLOAT        NOT NULL,
                     UNIQUE         (version),
                     PRIMARY KEY    (id)
-                ) ENGINE=InnoDB CHARACTER SET=utf8;`
+                ) ENGINE=InnoDB CHARACTER SET=utf8;`, m.Options.tableName())
 }
 
 // InsertSQL returns the SQL to insert a new migration in the schema table
 func (m MySQLDialect) InsertSQL() string {
-	return `INSERT INTO db_version
+	return fmt.Sprintf(`INSERT INTO %s
                 (
                     version,
                     description,
@@ -28,18 +35,52 @@ func (m MySQLDialect) InsertSQL() string {
                     applied_at,
                     execution_time
                 )
-            VALUES (?, ?, ?, ?, ?);`
+            VALUES (?, ?, ?, ?, ?);`, m.Options.tableName())
 }
 
 // AllSQL returns a SQL to get all entries in the table
 func (m MySQLDialect) AllSQL() string {
-	return `SELECT 
+	return fmt.Sprintf(`SELECT
                 version,
                 description,
                 checksum,
                 applied_at,
                 execution_time
-            FROM 
-                db_version
-            ORDER BY version ASC;`
+            FROM
+                %s
+            ORDER BY version ASC;`, m.Options.tableName())
+}
+
+// DeleteSQL returns the SQL to remove a migration from the schema table
+func (m MySQLDialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = ?;`, m.Options.tableName())
+}
+
+// LockSQL returns the SQL used to acquire MySQL's GET_LOCK advisory lock,
+// it blocks server-side until acquired or the query is canceled.
+func (m MySQLDialect) LockSQL() string {
+	return fmt.Sprintf(`SELECT GET_LOCK('%s', 86400);`, m.Options.tableName())
+}
+
+// UnlockSQL returns the SQL used to release the lock acquired by LockSQL.
+func (m MySQLDialect) UnlockSQL() string {
+	return fmt.Sprintf(`SELECT RELEASE_LOCK('%s');`, m.Options.tableName())
+}
+
+// CheckLockResult reads GET_LOCK's result: 1 when the lock was acquired,
+// 0 when it timed out, NULL on error. Unlike a failed query, a 0 or NULL
+// result does not surface as a query error, so GenericDriver.Lock must
+// inspect it explicitly instead of assuming success whenever LockSQL ran
+// without error.
+func (m MySQLDialect) CheckLockResult(row *sql.Row) error {
+	var result sql.NullInt64
+	if err := row.Scan(&result); err != nil {
+		return err
+	}
+
+	if !result.Valid || result.Int64 != 1 {
+		return ErrLockTimeout
+	}
+
+	return nil
 }
@@ -0,0 +1,68 @@
+package darwin
+
+import "fmt"
+
+// PostgresDialect a Dialect configured for PostgreSQL
+type PostgresDialect struct {
+	Options DialectOptions
+}
+
+// CreateTableSQL returns the SQL to create the schema table
+func (p PostgresDialect) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    id             BIGSERIAL    PRIMARY KEY,
+                    version        FLOAT        NOT NULL UNIQUE,
+                    description    VARCHAR(255) NOT NULL,
+                    checksum       VARCHAR(32)  NOT NULL,
+                    applied_at     TIMESTAMPTZ  NOT NULL,
+                    execution_time FLOAT        NOT NULL
+                );`, p.Options.tableName())
+}
+
+// InsertSQL returns the SQL to insert a new migration in the schema table
+func (p PostgresDialect) InsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time
+                )
+            VALUES ($1, $2, $3, $4, $5);`, p.Options.tableName())
+}
+
+// AllSQL returns a SQL to get all entries in the table
+func (p PostgresDialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time
+            FROM
+                %s
+            ORDER BY version ASC;`, p.Options.tableName())
+}
+
+// DeleteSQL returns the SQL to remove a migration from the schema table
+func (p PostgresDialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = $1;`, p.Options.tableName())
+}
+
+// LockSQL returns the SQL used to acquire a Postgres advisory lock keyed
+// by a hash of the table name, it blocks until acquired or canceled.
+func (p PostgresDialect) LockSQL() string {
+	return fmt.Sprintf(`SELECT pg_advisory_lock(hashtext('%s'));`, p.Options.tableName())
+}
+
+// UnlockSQL returns the SQL used to release the lock acquired by LockSQL.
+func (p PostgresDialect) UnlockSQL() string {
+	return fmt.Sprintf(`SELECT pg_advisory_unlock(hashtext('%s'));`, p.Options.tableName())
+}
+
+// nativeAppliedAt marks PostgresDialect as a NativeTimeDialect: its
+// applied_at column is TIMESTAMPTZ, a genuine timestamp, not the integer
+// Unix timestamp other dialects use.
+func (p PostgresDialect) nativeAppliedAt() {}
@@ -0,0 +1,193 @@
+package darwin
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// stubDB backs a minimal database/sql/driver.Driver good enough to run
+// GenericDriver's CreateContext/InsertContext/AllContext/Delete and
+// Lock/Unlock through the real database/sql bind/scan machinery, instead
+// of fakeDriver's direct Go values. It dispatches on substrings of the
+// SQL text rather than parsing it, since placeholder syntax differs per
+// dialect but database/sql already hands args to us positionally.
+type stubDB struct {
+	mu   sync.Mutex
+	rows [][5]driver.Value
+
+	// lockResult is returned as the single row/column of a LockChecker
+	// dialect's LockSQL (e.g. MySQL's GET_LOCK).
+	lockResult driver.Value
+	// failLockAttempts is how many times a PollingLocker dialect's
+	// row-insert based LockSQL fails before it succeeds.
+	failLockAttempts int
+	lockAttempts     int
+}
+
+func (s *stubDB) exec(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	switch {
+	case strings.Contains(query, "_lock"):
+		return s.execLock(query)
+	case strings.Contains(query, "CREATE TABLE"):
+		return stubResult{}, nil
+	case strings.Contains(query, "INSERT INTO"):
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		var row [5]driver.Value
+		for i, a := range args {
+			row[i] = a.Value
+		}
+		s.rows = append(s.rows, row)
+
+		return stubResult{rowsAffected: 1}, nil
+	case strings.Contains(query, "DELETE FROM"):
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if len(args) > 0 {
+			for i, row := range s.rows {
+				if row[0] == args[0].Value {
+					s.rows = append(s.rows[:i], s.rows[i+1:]...)
+					break
+				}
+			}
+		}
+
+		return stubResult{rowsAffected: 1}, nil
+	default:
+		// RELEASE_LOCK / pg_advisory_unlock and any other blocking Locker
+		// statement that doesn't need state here. pg_advisory_lock contains
+		// "_lock", so it reaches execLock's default branch instead.
+		return stubResult{}, nil
+	}
+}
+
+// execLock backs SQLite3Dialect's fallback row-insert lock: it fails
+// with a constraint-like error failLockAttempts times before succeeding,
+// simulating contention that GenericDriver's PollingLocker retry loop
+// must poll through.
+func (s *stubDB) execLock(query string) (driver.Result, error) {
+	switch {
+	case strings.Contains(query, "INSERT INTO"):
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.lockAttempts++
+		if s.lockAttempts <= s.failLockAttempts {
+			return nil, errors.New("stubDB: UNIQUE constraint failed")
+		}
+
+		return stubResult{}, nil
+	default:
+		return stubResult{}, nil
+	}
+}
+
+func (s *stubDB) query(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	switch {
+	case strings.Contains(query, "GET_LOCK"):
+		return &stubRows{cols: []string{"lock"}, data: [][]driver.Value{{s.lockResult}}}, nil
+	case strings.Contains(query, "SELECT"):
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		rows := &stubRows{cols: []string{"version", "description", "checksum", "applied_at", "execution_time"}}
+		for _, row := range s.rows {
+			rows.data = append(rows.data, row[:])
+		}
+
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("stubDB: unhandled query %q", query)
+	}
+}
+
+type stubResult struct {
+	rowsAffected int64
+}
+
+func (r stubResult) LastInsertId() (int64, error) { return 0, nil }
+func (r stubResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type stubRows struct {
+	cols []string
+	data [][]driver.Value
+	pos  int
+}
+
+func (r *stubRows) Columns() []string { return r.cols }
+func (r *stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.data) {
+		return io.EOF
+	}
+
+	copy(dest, r.data[r.pos])
+	r.pos++
+
+	return nil
+}
+
+// stubConn adapts a stubDB to driver.Conn, driver.ExecerContext and
+// driver.QueryerContext.
+type stubConn struct {
+	db *stubDB
+}
+
+func (c stubConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("stubConn: Prepare not supported, use ExecerContext/QueryerContext (query: %q)", query)
+}
+
+func (c stubConn) Close() error { return nil }
+
+func (c stubConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("stubConn: transactions not supported")
+}
+
+func (c stubConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.db.exec(ctx, query, args)
+}
+
+func (c stubConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(ctx, query, args)
+}
+
+// stubSQLDriver is a driver.Driver that always opens a connection onto
+// the stubDB it was registered for.
+type stubSQLDriver struct {
+	db *stubDB
+}
+
+func (d stubSQLDriver) Open(string) (driver.Conn, error) {
+	return stubConn{db: d.db}, nil
+}
+
+var stubDriverCounter int64
+
+// openStubDB registers a fresh driver name for db and returns a *sql.DB
+// backed by it, closed automatically when t ends.
+func openStubDB(t *testing.T, db *stubDB) *sql.DB {
+	t.Helper()
+
+	name := fmt.Sprintf("darwin-stub-%d", atomic.AddInt64(&stubDriverCounter, 1))
+	sql.Register(name, stubSQLDriver{db: db})
+
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatalf("sql.Open(%q): %v", name, err)
+	}
+
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return sqlDB
+}
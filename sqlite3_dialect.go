@@ -0,0 +1,86 @@
+package darwin
+
+import (
+	"fmt"
+	"time"
+)
+
+// lockPollInterval is how often GenericDriver.Lock retries the fallback
+// lock insert while another connection holds it.
+const lockPollInterval = 50 * time.Millisecond
+
+// SQLite3Dialect a Dialect configured for SQLite
+type SQLite3Dialect struct {
+	Options DialectOptions
+}
+
+// CreateTableSQL returns the SQL to create the schema table and, since
+// SQLite has no advisory locks, the fallback row-based lock table used
+// by LockSQL/UnlockSQL.
+func (s SQLite3Dialect) CreateTableSQL() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+                (
+                    id             INTEGER      PRIMARY KEY AUTOINCREMENT,
+                    version        FLOAT        NOT NULL UNIQUE,
+                    description    VARCHAR(255) NOT NULL,
+                    checksum       VARCHAR(32)  NOT NULL,
+                    applied_at     INTEGER      NOT NULL,
+                    execution_time FLOAT        NOT NULL
+                );
+            CREATE TABLE IF NOT EXISTS %s (id INTEGER PRIMARY KEY);`, s.Options.tableName(), s.lockTableName())
+}
+
+// InsertSQL returns the SQL to insert a new migration in the schema table
+func (s SQLite3Dialect) InsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time
+                )
+            VALUES (?, ?, ?, ?, ?);`, s.Options.tableName())
+}
+
+// AllSQL returns a SQL to get all entries in the table
+func (s SQLite3Dialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time
+            FROM
+                %s
+            ORDER BY version ASC;`, s.Options.tableName())
+}
+
+// DeleteSQL returns the SQL to remove a migration from the schema table
+func (s SQLite3Dialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = ?;`, s.Options.tableName())
+}
+
+// LockSQL returns the SQL used to acquire SQLite's fallback row-based
+// lock: inserting the lock table's single row. SQLite has no advisory
+// locks, and the insert fails immediately with a UNIQUE constraint
+// violation on contention rather than blocking, so GenericDriver.Lock
+// retries it on LockPollInterval instead of running it once.
+func (s SQLite3Dialect) LockSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s (id) VALUES (1);`, s.lockTableName())
+}
+
+// UnlockSQL returns the SQL used to release the lock acquired by LockSQL.
+func (s SQLite3Dialect) UnlockSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE id = 1;`, s.lockTableName())
+}
+
+// LockPollInterval reports how often GenericDriver.Lock retries LockSQL;
+// it marks SQLite3Dialect as a PollingLocker.
+func (s SQLite3Dialect) LockPollInterval() time.Duration {
+	return lockPollInterval
+}
+
+func (s SQLite3Dialect) lockTableName() string {
+	return s.Options.tableName() + "_lock"
+}
@@ -0,0 +1,53 @@
+package darwin
+
+import "fmt"
+
+// SQLServerDialect a Dialect configured for Microsoft SQL Server
+type SQLServerDialect struct {
+	Options DialectOptions
+}
+
+// CreateTableSQL returns the SQL to create the schema table
+func (s SQLServerDialect) CreateTableSQL() string {
+	return fmt.Sprintf(`IF OBJECT_ID('%[1]s', 'U') IS NULL
+            CREATE TABLE %[1]s
+                (
+                    id             INT           IDENTITY(1,1) PRIMARY KEY,
+                    version        FLOAT         NOT NULL UNIQUE,
+                    description    VARCHAR(255)  NOT NULL,
+                    checksum       VARCHAR(32)   NOT NULL,
+                    applied_at     BIGINT        NOT NULL,
+                    execution_time FLOAT         NOT NULL
+                );`, s.Options.tableName())
+}
+
+// InsertSQL returns the SQL to insert a new migration in the schema table
+func (s SQLServerDialect) InsertSQL() string {
+	return fmt.Sprintf(`INSERT INTO %s
+                (
+                    version,
+                    description,
+                    checksum,
+                    applied_at,
+                    execution_time
+                )
+            VALUES (@p1, @p2, @p3, @p4, @p5);`, s.Options.tableName())
+}
+
+// AllSQL returns a SQL to get all entries in the table
+func (s SQLServerDialect) AllSQL() string {
+	return fmt.Sprintf(`SELECT
+                version,
+                description,
+                checksum,
+                applied_at,
+                execution_time
+            FROM
+                %s
+            ORDER BY version ASC;`, s.Options.tableName())
+}
+
+// DeleteSQL returns the SQL to remove a migration from the schema table
+func (s SQLServerDialect) DeleteSQL() string {
+	return fmt.Sprintf(`DELETE FROM %s WHERE version = @p1;`, s.Options.tableName())
+}